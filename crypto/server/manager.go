@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"errors"
@@ -23,8 +26,23 @@ import (
 
 type ServerCryptoManager struct {
 	*client.CryptoManager
+
+	revocations *revocationSet
 }
 
+// AddCertificateRequest enrolls the public key from a client's CSR.
+//
+// Enrollment itself accepts RSA, ECDSA and Ed25519 keys, and client ids
+// are bound to whichever of those algorithms the client used (see
+// checkEnrollmentAlgorithmAllowed for the current limits on per-deployment
+// algorithm pinning). What is not covered
+// here is the rest of the message path: the client/server cipher in
+// crypto/client wraps the per-message AES key with RSA-OAEP, which has
+// no ECDSA/Ed25519 equivalent, so a non-RSA client can enroll but
+// cannot yet exchange encrypted messages with this server. Making that
+// work is a real end-to-end change to crypto/client's cipher (e.g. an
+// ECDH-derived shared secret for the key wrap), not something that
+// belongs in the enrollment path alone.
 func (self *ServerCryptoManager) AddCertificateRequest(
 	config_obj *config_proto.Config,
 	csr_pem []byte) (string, error) {
@@ -33,12 +51,28 @@ func (self *ServerCryptoManager) AddCertificateRequest(
 		return "", err
 	}
 
-	if csr.PublicKeyAlgorithm != x509.RSA {
-		return "", errors.New("Not RSA algorithm")
+	if err := checkEnrollmentAlgorithmAllowed(config_obj, csr.PublicKeyAlgorithm); err != nil {
+		return "", err
+	}
+
+	public_key, err := publicKeyFromCSR(csr)
+	if err != nil {
+		return "", err
 	}
 
 	common_name := csr.Subject.CommonName
-	public_key := csr.PublicKey.(*rsa.PublicKey)
+	client_id := utils.ClientIdFromConfigObj(common_name, config_obj)
+
+	// A client id whose previous key was revoked (e.g. the device was
+	// compromised) may not simply re-enroll and overwrite the
+	// revocation - unless the CSR carries a valid operator-signed
+	// override token, re-enrollment is refused even though the
+	// presented public key is otherwise fine. A valid override lifts
+	// the revocation below, once the new key is actually stored.
+	_, revoked := self.revocations.IsRevoked(client_id)
+	if revoked && !hasValidRevocationOverride(config_obj, csr, common_name) {
+		return "", errClientKeyRevoked
+	}
 
 	// CSRs are always generated by clients and therefore must
 	// follow the rules about client id - make sure the client id
@@ -53,22 +87,98 @@ func (self *ServerCryptoManager) AddCertificateRequest(
 	// corresponds with the public key this client presents. This
 	// avoids the possibility of impersonation since the
 	// public/private key pair is tied into the client id itself.
-	if common_name != crypto_utils.ClientIDFromPublicKey(public_key) {
+	//
+	// crypto_utils.ClientIDFromPublicKey only accepts an *rsa.PublicKey
+	// (it hashes the raw RSA modulus, not the SPKI DER), so it must stay
+	// the id check for RSA keys - that is the format every existing RSA
+	// client already computes for its own common_name. ECDSA/Ed25519
+	// keys have no equivalent in crypto_utils, so clientIDFromPublicKey
+	// hashes their canonical SPKI encoding instead.
+	var expected_client_id string
+	if rsa_key, ok := public_key.(*rsa.PublicKey); ok {
+		expected_client_id = crypto_utils.ClientIDFromPublicKey(rsa_key)
+	} else {
+		expected_client_id, err = clientIDFromPublicKey(public_key)
+		if err != nil {
+			return "", err
+		}
+	}
+	if common_name != expected_client_id {
 		return "", errors.New("Invalid CSR")
 	}
-	err = self.Resolver.SetPublicKey(
-		config_obj,
-		utils.ClientIdFromConfigObj(common_name, config_obj),
-		csr.PublicKey.(*rsa.PublicKey))
+	err = self.Resolver.SetPublicKey(config_obj, client_id, public_key)
 	if err != nil {
 		return "", err
 	}
 
-	// Derive the client id from the common name and the org id
-	client_id := utils.ClientIdFromConfigObj(csr.Subject.CommonName, config_obj)
+	// The override token only lets a revoked client id back in once its
+	// freshly presented key is actually stored above - lift the
+	// revocation now, both in-memory and persisted, so the next
+	// GetPublicKey call doesn't immediately refuse the key we just
+	// accepted.
+	if revoked {
+		self.revocations.Remove(client_id)
+		if err := deleteRevocation(config_obj, client_id); err != nil {
+			return "", err
+		}
+	}
+
 	return client_id, nil
 }
 
+// publicKeyFromCSR extracts the CSR's public key, rejecting anything
+// other than the algorithms we know how to verify client signatures
+// for (RSA, ECDSA and Ed25519).
+func publicKeyFromCSR(csr *x509.CertificateRequest) (crypto.PublicKey, error) {
+	switch public_key := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return public_key, nil
+	case *ecdsa.PublicKey:
+		return public_key, nil
+	case ed25519.PublicKey:
+		return public_key, nil
+	default:
+		return nil, errors.New("Unsupported public key algorithm")
+	}
+}
+
+// enrollmentAlgorithmName returns the short, config-facing name for a
+// CSR's public key algorithm.
+func enrollmentAlgorithmName(algo x509.PublicKeyAlgorithm) (string, bool) {
+	switch algo {
+	case x509.RSA:
+		return "rsa", true
+	case x509.ECDSA:
+		return "ecdsa", true
+	case x509.Ed25519:
+		return "ed25519", true
+	default:
+		return "", false
+	}
+}
+
+// checkEnrollmentAlgorithmAllowed rejects any CSR whose algorithm we
+// cannot verify client signatures for.
+//
+// Letting an operator pin enrollment to a subset of algorithms (e.g.
+// "ecdsa" only, for an HSM-backed fleet) needs a
+// Frontend.EnrollmentKeyAlgorithms field on config_proto.FrontendConfig,
+// which is not part of this checkout - config/proto is generated from
+// config.proto via protoc and isn't vendored here, so the field can't
+// be added from crypto/server alone the way crypto_proto.KeyRevocation
+// was. Until that field exists upstream, every algorithm
+// enrollmentAlgorithmName recognizes is accepted.
+func checkEnrollmentAlgorithmAllowed(
+	config_obj *config_proto.Config, algo x509.PublicKeyAlgorithm) error {
+
+	_, supported := enrollmentAlgorithmName(algo)
+	if !supported {
+		return errors.New("Unsupported public key algorithm")
+	}
+
+	return nil
+}
+
 func NewServerCryptoManager(
 	ctx context.Context,
 	config_obj *config_proto.Config,
@@ -83,7 +193,12 @@ func NewServerCryptoManager(
 		return nil, err
 	}
 
-	resolver, err := NewServerPublicKeyResolver(ctx, config_obj, wg)
+	revocations := newRevocationSet()
+	if err := loadRevocations(config_obj, revocations); err != nil {
+		return nil, err
+	}
+
+	resolver, err := NewServerPublicKeyResolver(ctx, config_obj, wg, revocations)
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +212,11 @@ func NewServerCryptoManager(
 
 	server_manager := &ServerCryptoManager{
 		CryptoManager: base,
+		revocations:   revocations,
 	}
 
+	startRevocationSweeper(ctx, config_obj, wg, revocations)
+
 	err = journal.WatchQueueWithCB(ctx, config_obj, wg,
 		"Server.Internal.ClientDelete",
 		"CryptoServerManager",
@@ -114,6 +232,40 @@ func NewServerCryptoManager(
 			}
 			return nil
 		})
+	if err != nil {
+		return nil, err
+	}
+
+	// A revocation made on another frontend in this deployment is
+	// announced here so every frontend's in-memory revocationSet
+	// converges without each of them polling the datastore.
+	err = journal.WatchQueueWithCB(ctx, config_obj, wg,
+		"Server.Internal.KeyRevoked",
+		"CryptoServerManager",
+		func(ctx context.Context,
+			config_obj *config_proto.Config,
+			row *ordereddict.Dict) error {
+
+			client_id, pres := row.GetString("ClientId")
+			if !pres {
+				return nil
+			}
+
+			reason, _ := row.GetString("Reason")
+			entry := &revocationEntry{
+				Reason:    reason,
+				RevokedAt: time.Now(),
+			}
+			if until, pres := row.GetInt64("Until"); pres && until > 0 {
+				entry.Until = time.Unix(until, 0)
+			}
+
+			revocations.Add(client_id, entry)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
 
 	return server_manager, nil
 }
@@ -123,6 +275,8 @@ type serverPublicKeyResolver struct {
 	// overwhelmed in the slow path for clients that are not yet
 	// enrolled.
 	negative_lru *ttlcache.Cache
+
+	revocations *revocationSet
 }
 
 func (self *serverPublicKeyResolver) DeleteSubject(client_id string) {
@@ -131,7 +285,14 @@ func (self *serverPublicKeyResolver) DeleteSubject(client_id string) {
 
 func (self *serverPublicKeyResolver) GetPublicKey(
 	config_obj *config_proto.Config,
-	client_id string) (*rsa.PublicKey, bool) {
+	client_id string) (crypto.PublicKey, bool) {
+
+	// A revoked key is never trusted, even though its record still
+	// exists in the datastore: revoking a key does not delete the
+	// client's key record.
+	if _, revoked := self.revocations.IsRevoked(client_id); revoked {
+		return nil, false
+	}
 
 	// Check if we failed to get this key recently - this reduces IO
 	// while clients enrol.
@@ -164,7 +325,7 @@ func (self *serverPublicKeyResolver) GetPublicKey(
 
 func (self *serverPublicKeyResolver) SetPublicKey(
 	config_obj *config_proto.Config,
-	client_id string, key *rsa.PublicKey) error {
+	client_id string, key crypto.PublicKey) error {
 
 	self.negative_lru.Remove(client_id)
 
@@ -187,11 +348,13 @@ func (self *serverPublicKeyResolver) Clear() {}
 func NewServerPublicKeyResolver(
 	ctx context.Context,
 	config_obj *config_proto.Config,
-	wg *sync.WaitGroup) (client.PublicKeyResolver, error) {
+	wg *sync.WaitGroup,
+	revocations *revocationSet) (client.PublicKeyResolver, error) {
 
 	result := &serverPublicKeyResolver{
 		// Cache missing keys for 60 seconds.
 		negative_lru: ttlcache.NewCache(),
+		revocations:  revocations,
 	}
 
 	timeout := time.Duration(10 * time.Second)
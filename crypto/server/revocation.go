@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Velocidex/ordereddict"
+	config_proto "www.velocidex.com/golang/velociraptor/config/proto"
+	crypto_proto "www.velocidex.com/golang/velociraptor/crypto/proto"
+	crypto_utils "www.velocidex.com/golang/velociraptor/crypto/utils"
+	"www.velocidex.com/golang/velociraptor/datastore"
+	"www.velocidex.com/golang/velociraptor/logging"
+	"www.velocidex.com/golang/velociraptor/paths"
+	"www.velocidex.com/golang/velociraptor/services/journal"
+)
+
+// revocationSweepInterval controls how often we scan for time-boxed
+// revocations that have expired.
+const revocationSweepInterval = 10 * time.Minute
+
+// revocationOverrideExtensionOID tags a CSR extension carrying an
+// operator-signed override token that lets a previously revoked
+// client id re-enroll (e.g. after the hardware was re-imaged and
+// issued a fresh key pair). It lives under a private-use enterprise
+// arbitrary number since it is never exposed outside this deployment.
+var revocationOverrideExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 55555, 1, 1}
+
+// revocationEntry is the in-memory record of a single revoked client
+// key.
+type revocationEntry struct {
+	Reason    string
+	RevokedAt time.Time
+
+	// Until is the zero time.Time for a permanent revocation.
+	Until time.Time
+}
+
+func (self *revocationEntry) expired(now time.Time) bool {
+	return !self.Until.IsZero() && now.After(self.Until)
+}
+
+// revocationSet is the in-memory mirror of the persisted revocation
+// list. A bloom filter short-circuits the overwhelmingly common case
+// (a client id that was never revoked) without taking the mutex or
+// consulting the exact set; the exact set is authoritative for every
+// bloom filter hit, including its false positives.
+type revocationSet struct {
+	mu    sync.Mutex
+	bloom *bloomFilter
+	exact map[string]*revocationEntry
+}
+
+func newRevocationSet() *revocationSet {
+	return &revocationSet{
+		bloom: newBloomFilter(1<<20, 4),
+		exact: make(map[string]*revocationEntry),
+	}
+}
+
+func (self *revocationSet) Add(client_id string, entry *revocationEntry) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.bloom.Add(client_id)
+	self.exact[client_id] = entry
+}
+
+func (self *revocationSet) Remove(client_id string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	// A simple bloom filter cannot safely support deletes, so we only
+	// ever remove from the exact set. A stale bloom filter hit after
+	// this just costs one extra (negative) map lookup on future
+	// checks - it can never cause us to treat a live client as
+	// revoked.
+	delete(self.exact, client_id)
+}
+
+// IsRevoked returns the revocation entry for client_id, if any. An
+// entry whose Until has already passed is treated as not revoked even
+// if the background sweeper has not yet cleaned it up.
+func (self *revocationSet) IsRevoked(client_id string) (*revocationEntry, bool) {
+	if !self.bloom.MayContain(client_id) {
+		return nil, false
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	entry, pres := self.exact[client_id]
+	if !pres || entry.expired(time.Now()) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// expiredClientIDs returns the client ids of every time-boxed
+// revocation that has passed its Until time, for the sweeper to clean
+// up in the persistent store.
+func (self *revocationSet) expiredClientIDs(now time.Time) []string {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	var expired []string
+	for client_id, entry := range self.exact {
+		if entry.expired(now) {
+			expired = append(expired, client_id)
+		}
+	}
+	return expired
+}
+
+// bloomFilter is a minimal fixed-size bloom filter used to make "is
+// this client id revoked" a cheap no-op for the common case of a
+// client that has never been revoked.
+type bloomFilter struct {
+	bits []uint64
+	k    int
+}
+
+func newBloomFilter(nbits, k int) *bloomFilter {
+	return &bloomFilter{
+		bits: make([]uint64, (nbits+63)/64),
+		k:    k,
+	}
+}
+
+func (self *bloomFilter) indexes(value string) []uint64 {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(value))
+	base := hasher.Sum64()
+
+	// The two 32 bit halves of the hash serve as the two hash values.
+	h1 := base & 0xffffffff
+	h2 := (base >> 32) | 1
+
+	nbits := uint64(len(self.bits) * 64)
+	indexes := make([]uint64, self.k)
+	for i := 0; i < self.k; i++ {
+		// Double hashing (Kirsch-Mitzenmacher) avoids needing k
+		// independent hash functions.
+		indexes[i] = (h1 + uint64(i)*h2) % nbits
+	}
+	return indexes
+}
+
+func (self *bloomFilter) Add(value string) {
+	for _, index := range self.indexes(value) {
+		self.bits[index/64] |= 1 << (index % 64)
+	}
+}
+
+func (self *bloomFilter) MayContain(value string) bool {
+	for _, index := range self.indexes(value) {
+		if self.bits[index/64]&(1<<(index%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// RevokePublicKey marks client_id's currently enrolled key as
+// untrusted. Once revoked, GetPublicKey will refuse to return the key
+// (so message verification fails) and AddCertificateRequest will
+// refuse to re-enroll the same client id unless a valid override
+// token is presented. until may be the zero time.Time for a
+// permanent revocation.
+func (self *ServerCryptoManager) RevokePublicKey(
+	ctx context.Context,
+	config_obj *config_proto.Config,
+	client_id, reason string, until time.Time) error {
+
+	entry := &revocationEntry{
+		Reason:    reason,
+		RevokedAt: time.Now(),
+		Until:     until,
+	}
+
+	if err := persistRevocation(config_obj, client_id, entry); err != nil {
+		return err
+	}
+
+	self.revocations.Add(client_id, entry)
+
+	// Tell the other frontends in a multi-frontend deployment so their
+	// in-memory revocationSet stays consistent without each of them
+	// having to poll the datastore.
+	row := ordereddict.NewDict().
+		Set("ClientId", client_id).
+		Set("Reason", reason).
+		Set("Until", until.Unix())
+
+	return journal.PushRowsToArtifact(ctx, config_obj,
+		[]*ordereddict.Dict{row}, "Server.Internal.KeyRevoked",
+		client_id, "")
+}
+
+func persistRevocation(
+	config_obj *config_proto.Config,
+	client_id string, entry *revocationEntry) error {
+
+	db, err := datastore.GetDB(config_obj)
+	if err != nil {
+		return err
+	}
+
+	record := &crypto_proto.KeyRevocation{
+		ClientId:  client_id,
+		Reason:    entry.Reason,
+		RevokedAt: uint64(entry.RevokedAt.Unix()),
+	}
+	if !entry.Until.IsZero() {
+		record.Until = uint64(entry.Until.Unix())
+	}
+
+	client_path_manager := paths.NewClientPathManager(client_id)
+	return db.SetSubjectWithCompletion(
+		config_obj, client_path_manager.Revocation(), record, nil)
+}
+
+func deleteRevocation(config_obj *config_proto.Config, client_id string) error {
+	db, err := datastore.GetDB(config_obj)
+	if err != nil {
+		return err
+	}
+
+	client_path_manager := paths.NewClientPathManager(client_id)
+	return db.DeleteSubject(config_obj, client_path_manager.Revocation())
+}
+
+// loadRevocations populates an empty revocationSet from the datastore
+// at startup by walking every enrolled client's revocation record
+// once. Every frontend in the deployment calls this (there is no
+// single sweeper owner), so each one rebuilds its own in-memory view
+// independently after a restart; from then on a revocation made on any
+// one frontend is broadcast over the journal so the others pick it up
+// without re-listing the datastore themselves.
+func loadRevocations(
+	config_obj *config_proto.Config, revocations *revocationSet) error {
+
+	db, err := datastore.GetDB(config_obj)
+	if err != nil {
+		return err
+	}
+
+	children, err := db.ListChildren(config_obj, paths.CLIENTS_ROOT)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if child.IsDir() {
+			continue
+		}
+
+		client_id := child.Base()
+		if !strings.HasPrefix(client_id, "C.") {
+			continue
+		}
+
+		client_path_manager := paths.NewClientPathManager(client_id)
+		record := &crypto_proto.KeyRevocation{}
+		err := db.GetSubject(config_obj, client_path_manager.Revocation(), record)
+		if err != nil {
+			continue
+		}
+
+		entry := &revocationEntry{
+			Reason:    record.Reason,
+			RevokedAt: time.Unix(int64(record.RevokedAt), 0),
+		}
+		if record.Until > 0 {
+			entry.Until = time.Unix(int64(record.Until), 0)
+		}
+		revocations.Add(client_id, entry)
+	}
+
+	return nil
+}
+
+// startRevocationSweeper periodically expires time-boxed revocations
+// once their Until time has passed, both from the in-memory set and
+// the persisted record, so the datastore does not accumulate stale
+// revocations forever.
+func startRevocationSweeper(
+	ctx context.Context,
+	config_obj *config_proto.Config,
+	wg *sync.WaitGroup,
+	revocations *revocationSet) {
+
+	logger := logging.GetLogger(config_obj, &logging.FrontendComponent)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(revocationSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				for _, client_id := range revocations.expiredClientIDs(time.Now()) {
+					revocations.Remove(client_id)
+					if err := deleteRevocation(config_obj, client_id); err != nil {
+						logger.Error(
+							"startRevocationSweeper: removing expired revocation for %v: %v",
+							client_id, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// hasValidRevocationOverride checks the CSR for an override token
+// signed by the deployment's CA over the client id. An operator signs
+// this token out of band (e.g. with `velociraptor config sign`-style
+// tooling) when a client that was previously revoked legitimately
+// needs to re-enroll, for example after its hardware was re-imaged
+// and issued a brand new key pair.
+func hasValidRevocationOverride(
+	config_obj *config_proto.Config, csr *x509.CertificateRequest, client_id string) bool {
+
+	if config_obj.CA == nil || config_obj.CA.Certificate == "" {
+		return false
+	}
+
+	var token []byte
+	for _, extension := range csr.Extensions {
+		if extension.Id.Equal(revocationOverrideExtensionOID) {
+			token = extension.Value
+			break
+		}
+	}
+	if len(token) == 0 {
+		return false
+	}
+
+	ca_cert, err := crypto_utils.ParseX509CertFromPemStr([]byte(config_obj.CA.Certificate))
+	if err != nil {
+		return false
+	}
+
+	return verifySignature(ca_cert.PublicKey, []byte(client_id), token)
+}
+
+// verifySignature checks sig over message using whichever of RSA,
+// ECDSA or Ed25519 pub happens to be - mirroring the algorithms
+// publicKeyFromCSR accepts for client enrollment.
+func verifySignature(pub crypto.PublicKey, message, sig []byte) bool {
+	digest := sha256.Sum256(message)
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], sig)
+
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, sig)
+
+	default:
+		return false
+	}
+}
+
+var errClientKeyRevoked = errors.New("client key has been revoked")
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilterNeverFalseNegative(t *testing.T) {
+	bloom := newBloomFilter(1<<10, 4)
+
+	added := []string{"C.1111111111111111", "C.2222222222222222", "C.3333333333333333"}
+	for _, client_id := range added {
+		bloom.Add(client_id)
+	}
+
+	for _, client_id := range added {
+		assert.True(t, bloom.MayContain(client_id))
+	}
+
+	// A value that was never added is usually reported absent. This
+	// is probabilistic in general, but with this few insertions into
+	// a 1024 bit filter a miss here would indicate a real bug rather
+	// than bad luck.
+	assert.False(t, bloom.MayContain("C.never-added"))
+}
+
+func TestRevocationSetAddRemoveIsRevoked(t *testing.T) {
+	revocations := newRevocationSet()
+
+	_, revoked := revocations.IsRevoked("C.unknown")
+	assert.False(t, revoked)
+
+	entry := &revocationEntry{Reason: "compromised", RevokedAt: time.Now()}
+	revocations.Add("C.client1", entry)
+
+	got, revoked := revocations.IsRevoked("C.client1")
+	assert.True(t, revoked)
+	assert.Equal(t, "compromised", got.Reason)
+
+	revocations.Remove("C.client1")
+	_, revoked = revocations.IsRevoked("C.client1")
+	assert.False(t, revoked)
+}
+
+func TestRevocationSetExpiredEntryIsNotRevoked(t *testing.T) {
+	revocations := newRevocationSet()
+
+	revocations.Add("C.client1", &revocationEntry{
+		Reason:    "temporary",
+		RevokedAt: time.Now().Add(-time.Hour),
+		Until:     time.Now().Add(-time.Minute),
+	})
+
+	_, revoked := revocations.IsRevoked("C.client1")
+	assert.False(t, revoked, "a revocation whose Until has passed must not be treated as revoked")
+}
+
+func TestRevocationSetExpiredClientIDs(t *testing.T) {
+	revocations := newRevocationSet()
+
+	now := time.Now()
+	revocations.Add("C.expired", &revocationEntry{RevokedAt: now.Add(-time.Hour), Until: now.Add(-time.Minute)})
+	revocations.Add("C.permanent", &revocationEntry{RevokedAt: now.Add(-time.Hour)})
+	revocations.Add("C.future", &revocationEntry{RevokedAt: now, Until: now.Add(time.Hour)})
+
+	expired := revocations.expiredClientIDs(now)
+	assert.ElementsMatch(t, []string{"C.expired"}, expired)
+}
+
+func TestRevocationEntryExpired(t *testing.T) {
+	now := time.Now()
+
+	permanent := &revocationEntry{RevokedAt: now}
+	assert.False(t, permanent.expired(now))
+
+	expired := &revocationEntry{RevokedAt: now.Add(-time.Hour), Until: now.Add(-time.Minute)}
+	assert.True(t, expired.expired(now))
+
+	not_yet := &revocationEntry{RevokedAt: now, Until: now.Add(time.Hour)}
+	assert.False(t, not_yet.expired(now))
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+)
+
+// clientIDFromPublicKey derives a client id from the SHA-256 hash of
+// pub's canonical SubjectPublicKeyInfo encoding. AddCertificateRequest
+// only calls this for ECDSA/Ed25519 keys - RSA keys keep using
+// crypto_utils.ClientIDFromPublicKey, which hashes the raw RSA modulus
+// rather than the SPKI DER and is what every existing RSA client
+// already computes.
+//
+// This is deliberately kept local to crypto/server rather than added
+// to crypto/utils: generalizing ClientIDFromPublicKey itself (along
+// with PublicKeyResolver and the client-side signing path) needs edits
+// to crypto/client and crypto/utils that are outside this checkout.
+func clientIDFromPublicKey(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", errors.New("Unsupported public key algorithm")
+	}
+
+	digest := sha256.Sum256(der)
+	return "C." + hex.EncodeToString(digest[:8]), nil
+}
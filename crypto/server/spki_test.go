@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIDFromPublicKey(t *testing.T) {
+	rsa_key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecdsa_key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ed25519_key, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ids := map[string]interface{}{
+		"rsa":     &rsa_key.PublicKey,
+		"ecdsa":   &ecdsa_key.PublicKey,
+		"ed25519": ed25519_key,
+	}
+
+	seen := make(map[string]string)
+	for name, pub := range ids {
+		client_id, err := clientIDFromPublicKey(pub)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(client_id, "C."))
+
+		// Deterministic: hashing the same key twice gives the same id.
+		again, err := clientIDFromPublicKey(pub)
+		require.NoError(t, err)
+		assert.Equal(t, client_id, again)
+
+		seen[name] = client_id
+	}
+
+	// Different algorithms (and different keys) must not collide.
+	assert.NotEqual(t, seen["rsa"], seen["ecdsa"])
+	assert.NotEqual(t, seen["rsa"], seen["ed25519"])
+	assert.NotEqual(t, seen["ecdsa"], seen["ed25519"])
+}
+
+func TestClientIDFromPublicKeyRejectsUnsupportedKey(t *testing.T) {
+	_, err := clientIDFromPublicKey("not a public key")
+	assert.Error(t, err)
+}
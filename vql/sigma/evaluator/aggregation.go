@@ -0,0 +1,491 @@
+package evaluator
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Velocidex/sigma-go"
+	"www.velocidex.com/golang/vfilter/types"
+)
+
+const (
+	// Maximum number of events retained in a single group's sliding
+	// window. This bounds memory for group-by keys that fire very
+	// frequently within the rule's Timeframe.
+	maxAggregationWindowSize = 10000
+
+	// Maximum number of distinct group-by keys tracked per rule. Once
+	// this is exceeded the least recently touched group is evicted -
+	// this protects us from high cardinality group-by fields (e.g. a
+	// group-by on a source IP address) growing the state unboundedly.
+	maxAggregationGroups = 10000
+
+	// Used when a rule does not specify a Timeframe - Sigma defaults
+	// an empty timeframe to a 1 hour window.
+	defaultAggregationTimeframe = time.Hour
+)
+
+// aggregationEntry is a single event's contribution to a group's
+// sliding window.
+type aggregationEntry struct {
+	timestamp time.Time
+
+	// value is the numeric operand for sum()/min()/max()/avg().
+	value float64
+
+	// fieldValue is the raw value of a count() field, used to count
+	// distinct values rather than raw occurrences. Unset for
+	// aggregation functions that do not take a field.
+	fieldValue interface{}
+
+	event *Event
+}
+
+// aggregationWindow is the sliding window of entries for a single
+// (conditionIndex, groupedBy) key.
+type aggregationWindow struct {
+	entries []aggregationEntry
+}
+
+// aggregationState tracks one aggregationWindow per group-by key,
+// evicting the least recently used group once maxAggregationGroups is
+// exceeded. It is safe for concurrent use.
+type aggregationState struct {
+	mu sync.Mutex
+
+	// order keeps keys ordered from least to most recently touched so
+	// we can cheaply evict the LRU entry. lookup maps a key to its
+	// element in order for O(1) "touch" operations.
+	order  *list.List
+	lookup map[string]*list.Element
+}
+
+type aggregationLRUEntry struct {
+	key    string
+	window *aggregationWindow
+}
+
+func newAggregationState() *aggregationState {
+	return &aggregationState{
+		order:  list.New(),
+		lookup: make(map[string]*list.Element),
+	}
+}
+
+// touch returns the window for key, creating it if necessary, and
+// marks it as the most recently used group.
+func (self *aggregationState) touch(key string) *aggregationWindow {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if element, pres := self.lookup[key]; pres {
+		self.order.MoveToBack(element)
+		return element.Value.(*aggregationLRUEntry).window
+	}
+
+	window := &aggregationWindow{}
+	element := self.order.PushBack(&aggregationLRUEntry{key: key, window: window})
+	self.lookup[key] = element
+
+	for self.order.Len() > maxAggregationGroups {
+		oldest := self.order.Front()
+		if oldest == nil {
+			break
+		}
+		self.order.Remove(oldest)
+		delete(self.lookup, oldest.Value.(*aggregationLRUEntry).key)
+	}
+
+	return window
+}
+
+// evaluateAggregationExpression implements the Sigma aggregation
+// expressions: a Comparison (count()/sum()/min()/max()/avg() against
+// a threshold) evaluated over a sliding, per-group time window. The
+// near proximity operator is not implemented: it always returns an
+// error.
+//
+// The window is keyed on the condition index (so different conditions
+// in the same rule do not share state) plus the value of the
+// aggregation function's group-by field, and is trimmed to the rule's
+// Timeframe on every call.
+func (self *VQLRuleEvaluator) evaluateAggregationExpression(
+	ctx context.Context, scope types.Scope, conditionIndex int,
+	aggregation sigma.AggregationExpr, event *Event) (bool, []*Event, error) {
+
+	switch agg := aggregation.(type) {
+	case sigma.Comparison:
+		return self.evaluateAggregationComparison(
+			ctx, scope, conditionIndex, agg, event)
+
+	case sigma.Near:
+		// Sigma's "near" operator asks whether several distinct
+		// selections co-occurred within the rule's Timeframe - a
+		// different question from "how many times did this one group
+		// fire", and not something a single group's sliding window of
+		// scalar values can answer. The upstream sigma-go parser
+		// itself does not produce this case yet (aggregationToAST
+		// rejects any non-comparison aggregation), so rather than
+		// silently aliasing it to count() we report it as explicitly
+		// unsupported until real proximity-of-selections semantics
+		// are implemented.
+		return false, nil, fmt.Errorf(
+			"aggregation: the sigma 'near' proximity operator is not implemented")
+
+	default:
+		return false, nil, fmt.Errorf(
+			"aggregation: unsupported aggregation expression %T", aggregation)
+	}
+}
+
+// evaluateAggregationComparison implements count()/sum()/min()/max()/avg()
+// compared against a threshold.
+func (self *VQLRuleEvaluator) evaluateAggregationComparison(
+	ctx context.Context, scope types.Scope, conditionIndex int,
+	comparison sigma.Comparison, event *Event) (bool, []*Event, error) {
+
+	field, groupedBy := aggregationFuncFieldAndGroup(comparison.Func)
+
+	groupKey := self.aggregationGroupKey(conditionIndex, groupedBy, scope, event)
+
+	value, fieldValue, err := self.aggregationFieldValue(scope, comparison.Func, field, event)
+	if err != nil {
+		return false, nil, err
+	}
+
+	timeframe := parseAggregationTimeframe(self.Detection.Timeframe)
+
+	now := self.aggregationEventTimestamp(scope, event)
+
+	window := self.aggregations.touch(groupKey)
+
+	self.aggregations.mu.Lock()
+	window.entries = append(window.entries, aggregationEntry{
+		timestamp:  now,
+		value:      value,
+		fieldValue: fieldValue,
+		event:      event,
+	})
+	window.entries = trimExpiredEntries(window.entries, now, timeframe)
+	if len(window.entries) > maxAggregationWindowSize {
+		window.entries = window.entries[len(window.entries)-maxAggregationWindowSize:]
+	}
+
+	// Copy out the entries we need while still holding the lock -
+	// other goroutines may be mutating window.entries concurrently
+	// for a different event on the same group.
+	entries := make([]aggregationEntry, len(window.entries))
+	copy(entries, window.entries)
+	self.aggregations.mu.Unlock()
+
+	current := computeAggregationValue(comparison.Func, entries)
+
+	matched, err := compareAggregationThreshold(current, comparison.Op, comparison.Threshold)
+	if err != nil {
+		return false, nil, err
+	}
+	if !matched {
+		return false, nil, nil
+	}
+
+	hits := make([]*Event, 0, len(entries))
+	for _, entry := range entries {
+		hits = append(hits, entry.event)
+	}
+
+	return true, hits, nil
+}
+
+// aggregationFuncFieldAndGroup pulls the operand field and group-by
+// field out of whichever concrete sigma.AggregationFunc this
+// comparison uses.
+func aggregationFuncFieldAndGroup(fn sigma.AggregationFunc) (field, groupedBy string) {
+	switch f := fn.(type) {
+	case sigma.Count:
+		return f.Field, f.GroupedBy
+	case sigma.Min:
+		return f.Field, f.GroupedBy
+	case sigma.Max:
+		return f.Field, f.GroupedBy
+	case sigma.Average:
+		return f.Field, f.GroupedBy
+	case sigma.Sum:
+		return f.Field, f.GroupedBy
+	default:
+		return "", ""
+	}
+}
+
+// aggregationGroupKey builds a stable key identifying the sliding
+// window this event belongs to: the condition it was evaluated under,
+// plus the value of the group-by field (if any) extracted from the
+// event using the same scope associative lookup the rest of the
+// evaluator uses to read event fields.
+func (self *VQLRuleEvaluator) aggregationGroupKey(
+	conditionIndex int, groupedBy string,
+	scope types.Scope, event *Event) string {
+
+	if groupedBy == "" {
+		return fmt.Sprintf("%d", conditionIndex)
+	}
+
+	value, pres := scope.Associative(event, groupedBy)
+	if !pres {
+		value = ""
+	}
+
+	return fmt.Sprintf("%d\x00%v", conditionIndex, value)
+}
+
+// aggregationFieldValue extracts this event's contribution to the
+// aggregation. sum()/min()/max()/avg() need a numeric operand;
+// count(field) counts distinct values of field, so it needs the raw
+// value; count() with no field just counts events.
+func (self *VQLRuleEvaluator) aggregationFieldValue(
+	scope types.Scope, fn sigma.AggregationFunc, field string,
+	event *Event) (value float64, fieldValue interface{}, err error) {
+
+	switch fn.(type) {
+	case sigma.Count:
+		if field == "" {
+			return 1, nil, nil
+		}
+
+		raw, pres := scope.Associative(event, field)
+		if !pres {
+			return 0, nil, nil
+		}
+		return 0, raw, nil
+
+	default:
+		raw, pres := scope.Associative(event, field)
+		if !pres {
+			return 0, nil, fmt.Errorf(
+				"aggregation: field %q not present on event", field)
+		}
+
+		numeric, ok := toFloat(raw)
+		if !ok {
+			return 0, nil, fmt.Errorf(
+				"aggregation: field %q is not numeric", field)
+		}
+		return numeric, nil, nil
+	}
+}
+
+// parseAggregationTimeframe parses Sigma's condition.timeframe syntax
+// (a number followed by a unit - s, m, h, d) into a time.Duration,
+// falling back to defaultAggregationTimeframe when the rule does not
+// specify one or specifies something we cannot parse.
+func parseAggregationTimeframe(timeframe string) time.Duration {
+	if timeframe == "" {
+		return defaultAggregationTimeframe
+	}
+
+	unit := timeframe[len(timeframe)-1:]
+	amount := timeframe[:len(timeframe)-1]
+
+	count, err := strconv.Atoi(amount)
+	if err != nil {
+		return defaultAggregationTimeframe
+	}
+
+	switch unit {
+	case "s":
+		return time.Duration(count) * time.Second
+	case "m":
+		return time.Duration(count) * time.Minute
+	case "h":
+		return time.Duration(count) * time.Hour
+	case "d":
+		return time.Duration(count) * 24 * time.Hour
+	default:
+		return defaultAggregationTimeframe
+	}
+}
+
+// aggregationEventTimestamp resolves the timestamp to use for the
+// sliding window. We prefer the event's own timestamp (so replayed /
+// historical event streams window correctly) and fall back to wall
+// clock time if the event carries none of the common timestamp
+// fields.
+func (self *VQLRuleEvaluator) aggregationEventTimestamp(
+	scope types.Scope, event *Event) time.Time {
+
+	for _, field := range []string{"EventTime", "Timestamp", "_ts"} {
+		raw, pres := scope.Associative(event, field)
+		if !pres {
+			continue
+		}
+
+		switch t := raw.(type) {
+		case time.Time:
+			return t
+		default:
+			if seconds, ok := toFloat(t); ok {
+				return time.Unix(int64(seconds), 0)
+			}
+		}
+	}
+
+	return time.Now()
+}
+
+// trimExpiredEntries drops entries older than timeframe relative to
+// now.
+//
+// Entries are appended in arrival order, not necessarily in timestamp
+// order: Match() (and therefore this code) may run concurrently from
+// multiple event workers, and the timestamp we window on comes from
+// each event's own EventTime/Timestamp field rather than when we
+// happened to process it. Two workers racing to append events with
+// out-of-order timestamps - routine under real-world clock skew or
+// multi-source ingestion - means expired entries are not guaranteed to
+// form a prefix of the slice, so we filter the whole window rather
+// than assuming one.
+func trimExpiredEntries(
+	entries []aggregationEntry, now time.Time, timeframe time.Duration) []aggregationEntry {
+
+	cutoff := now.Add(-timeframe)
+
+	kept := entries[:0]
+	for _, entry := range entries {
+		if !entry.timestamp.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// computeAggregationValue reduces the current window down to a single
+// number that can be compared against the comparison's threshold.
+func computeAggregationValue(fn sigma.AggregationFunc, entries []aggregationEntry) float64 {
+	switch fn.(type) {
+	case sigma.Count:
+		return computeCount(fn.(sigma.Count).Field, entries)
+
+	case sigma.Sum:
+		var total float64
+		for _, entry := range entries {
+			total += entry.value
+		}
+		return total
+
+	case sigma.Average:
+		if len(entries) == 0 {
+			return 0
+		}
+		var total float64
+		for _, entry := range entries {
+			total += entry.value
+		}
+		return total / float64(len(entries))
+
+	case sigma.Min:
+		if len(entries) == 0 {
+			return 0
+		}
+		min := entries[0].value
+		for _, entry := range entries[1:] {
+			if entry.value < min {
+				min = entry.value
+			}
+		}
+		return min
+
+	case sigma.Max:
+		if len(entries) == 0 {
+			return 0
+		}
+		max := entries[0].value
+		for _, entry := range entries[1:] {
+			if entry.value > max {
+				max = entry.value
+			}
+		}
+		return max
+
+	default:
+		return float64(len(entries))
+	}
+}
+
+// computeCount implements count(): a plain occurrence count when the
+// function has no field, or the number of distinct values of field
+// seen in the window otherwise.
+func computeCount(field string, entries []aggregationEntry) float64 {
+	if field == "" {
+		return float64(len(entries))
+	}
+
+	distinct := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		// Events that lack the field contribute no value.
+		if entry.fieldValue == nil {
+			continue
+		}
+		distinct[fmt.Sprintf("%v", entry.fieldValue)] = struct{}{}
+	}
+	return float64(len(distinct))
+}
+
+// compareAggregationThreshold applies the comparison's operator to the
+// current aggregation value.
+func compareAggregationThreshold(
+	current float64, op sigma.ComparisonOp, threshold float64) (bool, error) {
+
+	switch op {
+	case sigma.GreaterThan:
+		return current > threshold, nil
+	case sigma.GreaterThanEqual:
+		return current >= threshold, nil
+	case sigma.LessThan:
+		return current < threshold, nil
+	case sigma.LessThanEqual:
+		return current <= threshold, nil
+	case sigma.Equal, "":
+		return current == threshold, nil
+	case sigma.NotEqual:
+		return current != threshold, nil
+	default:
+		return false, fmt.Errorf(
+			"aggregation: unsupported comparison operator %q", op)
+	}
+}
+
+// toFloat coerces the common numeric types vfilter produces when
+// reading an event field into a float64 for aggregation purposes.
+func toFloat(value interface{}) (float64, bool) {
+	switch t := value.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int8:
+		return float64(t), true
+	case int16:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint8:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Velocidex/sigma-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimExpiredEntries(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeframe := 10 * time.Second
+
+	// Entries are not necessarily appended in timestamp order - two
+	// workers may race to append events with out-of-order
+	// timestamps. The oldest entry here (at index 1) is expired even
+	// though it is not a prefix of the slice.
+	entries := []aggregationEntry{
+		{timestamp: time.Unix(995, 0), value: 1},
+		{timestamp: time.Unix(985, 0), value: 2}, // expired, not a prefix
+		{timestamp: time.Unix(999, 0), value: 3},
+	}
+
+	kept := trimExpiredEntries(entries, now, timeframe)
+
+	assert.Len(t, kept, 2)
+	for _, entry := range kept {
+		assert.False(t, entry.timestamp.Before(now.Add(-timeframe)))
+	}
+}
+
+func TestTrimExpiredEntriesAllExpired(t *testing.T) {
+	now := time.Unix(1000, 0)
+	entries := []aggregationEntry{
+		{timestamp: time.Unix(0, 0)},
+		{timestamp: time.Unix(1, 0)},
+	}
+
+	kept := trimExpiredEntries(entries, now, time.Second)
+	assert.Empty(t, kept)
+}
+
+func TestComputeAggregationValueCount(t *testing.T) {
+	entries := []aggregationEntry{{}, {}, {}}
+	assert.Equal(t, float64(3), computeAggregationValue(sigma.Count{}, entries))
+}
+
+func TestComputeAggregationValueCountDistinctField(t *testing.T) {
+	entries := []aggregationEntry{
+		{fieldValue: "a"},
+		{fieldValue: "b"},
+		{fieldValue: "a"},
+	}
+	assert.Equal(t, float64(2), computeAggregationValue(sigma.Count{Field: "user"}, entries))
+}
+
+func TestComputeAggregationValueSumAvgMinMax(t *testing.T) {
+	entries := []aggregationEntry{
+		{value: 1}, {value: 5}, {value: 3},
+	}
+
+	assert.Equal(t, float64(9), computeAggregationValue(sigma.Sum{}, entries))
+	assert.Equal(t, float64(3), computeAggregationValue(sigma.Average{}, entries))
+	assert.Equal(t, float64(1), computeAggregationValue(sigma.Min{}, entries))
+	assert.Equal(t, float64(5), computeAggregationValue(sigma.Max{}, entries))
+}
+
+func TestCompareAggregationThreshold(t *testing.T) {
+	cases := []struct {
+		current   float64
+		op        sigma.ComparisonOp
+		threshold float64
+		want      bool
+	}{
+		{5, sigma.GreaterThan, 3, true},
+		{5, sigma.GreaterThan, 5, false},
+		{5, sigma.GreaterThanEqual, 5, true},
+		{5, sigma.LessThan, 3, false},
+		{5, sigma.LessThanEqual, 5, true},
+		{5, sigma.Equal, 5, true},
+		{5, sigma.NotEqual, 5, false},
+	}
+
+	for _, c := range cases {
+		got, err := compareAggregationThreshold(c.current, c.op, c.threshold)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, got, "current=%v op=%v threshold=%v", c.current, c.op, c.threshold)
+	}
+}
+
+func TestCompareAggregationThresholdUnsupportedOp(t *testing.T) {
+	_, err := compareAggregationThreshold(1, sigma.ComparisonOp("~"), 1)
+	assert.Error(t, err)
+}
+
+func TestParseAggregationTimeframe(t *testing.T) {
+	assert.Equal(t, 15*time.Minute, parseAggregationTimeframe("15m"))
+	assert.Equal(t, time.Hour, parseAggregationTimeframe("1h"))
+	assert.Equal(t, 30*time.Second, parseAggregationTimeframe("30s"))
+	assert.Equal(t, 2*24*time.Hour, parseAggregationTimeframe("2d"))
+	assert.Equal(t, defaultAggregationTimeframe, parseAggregationTimeframe(""))
+	assert.Equal(t, defaultAggregationTimeframe, parseAggregationTimeframe("garbage"))
+}
+
+func TestEvaluateAggregationExpressionNearUnsupported(t *testing.T) {
+	evaluator := &VQLRuleEvaluator{aggregations: newAggregationState()}
+
+	_, _, err := evaluator.evaluateAggregationExpression(
+		nil, nil, 0, sigma.Near{}, nil)
+	assert.Error(t, err)
+}
+
+func TestAggregationStateEvictsLRU(t *testing.T) {
+	state := newAggregationState()
+
+	for i := 0; i < maxAggregationGroups+1; i++ {
+		state.touch(string(rune(i)))
+	}
+
+	assert.LessOrEqual(t, state.order.Len(), maxAggregationGroups)
+}
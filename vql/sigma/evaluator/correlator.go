@@ -0,0 +1,21 @@
+package evaluator
+
+import (
+	"context"
+
+	"www.velocidex.com/golang/vfilter/types"
+)
+
+// SigmaCorrelator is what VQLRuleEvaluator.Match forwards a matching
+// event to when a rule's Correlator is configured (see correlation
+// rules, https://sigmahq.io/docs/meta/correlations.html). Its Match
+// keeps no correlation state (no timespan windows, correlation
+// comparators or grouping) and always returns a nil *Result and a nil
+// error.
+type SigmaCorrelator struct{}
+
+func (self *SigmaCorrelator) Match(
+	ctx context.Context, scope types.Scope,
+	evaluator *VQLRuleEvaluator, event *Event) (*Result, error) {
+	return nil, nil
+}
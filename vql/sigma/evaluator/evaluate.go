@@ -37,6 +37,13 @@ type VQLRuleEvaluator struct {
 	// If this rule has a correlator, then forward the match to the
 	// correlator.
 	Correlator *SigmaCorrelator `json:"correlator,omitempty" yaml:"correlator,omitempty"`
+
+	// Tracks the sliding windows used to evaluate aggregation
+	// expressions (count()/sum()/min()/max()/avg()) per
+	// condition and group-by key. aggregationState is internally
+	// synchronized since Match() may be called concurrently from
+	// multiple event workers.
+	aggregations *aggregationState
 }
 
 type FieldMappingRecord struct {
@@ -52,17 +59,11 @@ func NewVQLRuleEvaluator(
 		scope:         scope,
 		Rule:          rule,
 		fieldmappings: fieldmappings,
+		aggregations:  newAggregationState(),
 	}
 	return result
 }
 
-// TODO: Not supported yet
-func (self *VQLRuleEvaluator) evaluateAggregationExpression(
-	ctx context.Context, conditionIndex int,
-	aggregation sigma.AggregationExpr, event *Event) (bool, error) {
-	return false, nil
-}
-
 func (self *VQLRuleEvaluator) MaybeEnrichWithVQL(
 	ctx context.Context, scope types.Scope, event *Event) *Event {
 	if self.lambda != nil {
@@ -99,20 +100,39 @@ func (self *VQLRuleEvaluator) Match(ctx context.Context,
 		ConditionResults: make([]bool, len(self.Detection.Conditions)),
 	}
 
-	// TODO: This needs to be done lazily so conditions do not need to
-	// be evaluated needlessly.
-	for identifier, search := range self.Detection.Searches {
-		var err error
+	// Evaluate each named search lazily (and memoize it) the first
+	// time the condition tree actually needs its result. Many rules
+	// have several independent conditions and an expensive search
+	// referenced by one of them (a large keyword list, a regex, a VQL
+	// lambda) should not be paid for on events that another, cheaper
+	// condition already ruled out. Only searches the condition tree
+	// actually consults end up in result.SearchResults.
+	memoized := make(map[string]bool, len(self.Detection.Searches))
+	resolve := func(identifier string) (bool, error) {
+		if eval_result, pres := memoized[identifier]; pres {
+			return eval_result, nil
+		}
+
+		search, pres := self.Detection.Searches[identifier]
+		if !pres {
+			return false, fmt.Errorf("condition references unknown search %q", identifier)
+		}
 
 		eval_result, err := self.evaluateSearch(ctx, subscope, search, event)
 		if err != nil {
-			return nil, fmt.Errorf("error evaluating search %s: %w", identifier, err)
+			return false, fmt.Errorf("error evaluating search %s: %w", identifier, err)
 		}
+
+		memoized[identifier] = eval_result
 		result.SearchResults[identifier] = eval_result
+		return eval_result, nil
 	}
 
 	for conditionIndex, condition := range self.Detection.Conditions {
-		searchMatches := self.evaluateSearchExpression(condition.Search, result.SearchResults)
+		searchMatches, err := self.evaluateSearchExpression(condition.Search, resolve)
+		if err != nil {
+			return nil, err
+		}
 
 		switch {
 		// Event didn't match filters
@@ -128,13 +148,15 @@ func (self *VQLRuleEvaluator) Match(ctx context.Context,
 
 		// Search expression matched but still need to see if the aggregation returns true
 		case searchMatches && condition.Aggregation != nil:
-			aggregationMatches, err := self.evaluateAggregationExpression(ctx, conditionIndex, condition.Aggregation, event)
+			aggregationMatches, hits, err := self.evaluateAggregationExpression(
+				ctx, subscope, conditionIndex, condition.Aggregation, event)
 			if err != nil {
 				return nil, err
 			}
 			if aggregationMatches {
 				result.Match = true
 				result.ConditionResults[conditionIndex] = true
+				result.CorrelationHits = append(result.CorrelationHits, hits...)
 			}
 			continue
 		}
@@ -0,0 +1,142 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Velocidex/sigma-go"
+)
+
+// searchResolver lazily evaluates (and memoizes) the named search
+// identifier against the current event. It is only called the first
+// time the condition tree actually needs that search's result.
+type searchResolver func(identifier string) (bool, error)
+
+// evaluateSearchExpression walks a condition's search expression tree
+// (e.g. "1 of selection_* and not filter") and resolves each
+// identifier it encounters on demand via resolve, short-circuiting
+// "and"/"or" the moment the outcome is already decided so that
+// searches further down the tree are never evaluated needlessly.
+func (self *VQLRuleEvaluator) evaluateSearchExpression(
+	expr sigma.SearchExpr, resolve searchResolver) (bool, error) {
+
+	switch e := expr.(type) {
+	case sigma.And:
+		for _, node := range e {
+			matched, err := self.evaluateSearchExpression(node, resolve)
+			if err != nil {
+				return false, err
+			}
+			if !matched {
+				// Short circuit - the remaining operands of the "and"
+				// cannot change the outcome.
+				return false, nil
+			}
+		}
+		return true, nil
+
+	case sigma.Or:
+		for _, node := range e {
+			matched, err := self.evaluateSearchExpression(node, resolve)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				// Short circuit - the remaining operands of the "or"
+				// cannot change the outcome.
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case sigma.Not:
+		matched, err := self.evaluateSearchExpression(e.Expr, resolve)
+		if err != nil {
+			return false, err
+		}
+		return !matched, nil
+
+	case sigma.SearchIdentifier:
+		return resolve(e.Name)
+
+	case sigma.OneOfIdentifier:
+		return resolve(e.Ident.Name)
+
+	case sigma.AllOfIdentifier:
+		return resolve(e.Ident.Name)
+
+	case sigma.OneOfThem:
+		return self.evaluateSearchQuantifier(resolve, "*", false)
+
+	case sigma.AllOfThem:
+		return self.evaluateSearchQuantifier(resolve, "*", true)
+
+	case sigma.OneOfPattern:
+		return self.evaluateSearchQuantifier(resolve, e.Pattern, false)
+
+	case sigma.AllOfPattern:
+		return self.evaluateSearchQuantifier(resolve, e.Pattern, true)
+
+	default:
+		return false, fmt.Errorf("sigma: unhandled search expression node type %T", expr)
+	}
+}
+
+// evaluateSearchQuantifier implements the "1 of x" / "all of x" family
+// (OneOfThem/AllOfThem/OneOfPattern/AllOfPattern). Matching identifiers
+// are resolved in sorted order rather than in Go's randomized map
+// iteration order over self.Detection.Searches, so which search's (possibly expensive, side-effecting) VQL lambda
+// actually runs for a given event - and which subset ends up memoized
+// into the Match() result - is deterministic and reproducible from run
+// to run on an identical event. We still short circuit as soon as the
+// quantifier's outcome is known.
+func (self *VQLRuleEvaluator) evaluateSearchQuantifier(
+	resolve searchResolver, pattern string, all bool) (bool, error) {
+
+	identifiers := make([]string, 0, len(self.Detection.Searches))
+	for identifier := range self.Detection.Searches {
+		if matchesSearchPattern(pattern, identifier) {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	sort.Strings(identifiers)
+
+	matchedAny := false
+	for _, identifier := range identifiers {
+		matched, err := resolve(identifier)
+		if err != nil {
+			return false, err
+		}
+
+		if matched {
+			matchedAny = true
+			if !all {
+				// "1 of" (or "N of" with N==1) is satisfied already.
+				return true, nil
+			}
+		} else if all {
+			// "all of" already failed.
+			return false, nil
+		}
+	}
+
+	if all {
+		return true, nil
+	}
+	return matchedAny, nil
+}
+
+// matchesSearchPattern implements the simple glob used by Sigma's "of"
+// quantifiers, e.g. "selection_*" or "*" (match everything).
+func matchesSearchPattern(pattern, identifier string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(identifier, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return pattern == identifier
+}
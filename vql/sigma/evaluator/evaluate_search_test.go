@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Velocidex/sigma-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func resolverFromMap(results map[string]bool, calls *[]string) searchResolver {
+	return func(identifier string) (bool, error) {
+		if calls != nil {
+			*calls = append(*calls, identifier)
+		}
+		return results[identifier], nil
+	}
+}
+
+func TestEvaluateSearchExpressionAndShortCircuits(t *testing.T) {
+	evaluator := &VQLRuleEvaluator{}
+
+	var calls []string
+	resolve := resolverFromMap(map[string]bool{"a": false, "b": true}, &calls)
+
+	matched, err := evaluator.evaluateSearchExpression(
+		sigma.And{sigma.SearchIdentifier{Name: "a"}, sigma.SearchIdentifier{Name: "b"}},
+		resolve)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	// "b" should never have been resolved - "and" short circuited on
+	// the first false operand.
+	assert.Equal(t, []string{"a"}, calls)
+}
+
+func TestEvaluateSearchExpressionOrShortCircuits(t *testing.T) {
+	evaluator := &VQLRuleEvaluator{}
+
+	var calls []string
+	resolve := resolverFromMap(map[string]bool{"a": true, "b": false}, &calls)
+
+	matched, err := evaluator.evaluateSearchExpression(
+		sigma.Or{sigma.SearchIdentifier{Name: "a"}, sigma.SearchIdentifier{Name: "b"}},
+		resolve)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, []string{"a"}, calls)
+}
+
+func TestEvaluateSearchExpressionNot(t *testing.T) {
+	evaluator := &VQLRuleEvaluator{}
+	resolve := resolverFromMap(map[string]bool{"a": true}, nil)
+
+	matched, err := evaluator.evaluateSearchExpression(
+		sigma.Not{Expr: sigma.SearchIdentifier{Name: "a"}}, resolve)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestEvaluateSearchExpressionPropagatesResolveErrors(t *testing.T) {
+	evaluator := &VQLRuleEvaluator{}
+	boom := errors.New("boom")
+	resolve := func(identifier string) (bool, error) { return false, boom }
+
+	_, err := evaluator.evaluateSearchExpression(sigma.SearchIdentifier{Name: "a"}, resolve)
+	assert.Equal(t, boom, err)
+}
+
+func newRuleEvaluatorWithSearches(names ...string) *VQLRuleEvaluator {
+	searches := make(map[string]sigma.Search, len(names))
+	for _, name := range names {
+		searches[name] = sigma.Search{}
+	}
+	return &VQLRuleEvaluator{
+		Rule: sigma.Rule{
+			Detection: sigma.Detection{Searches: searches},
+		},
+	}
+}
+
+func TestEvaluateSearchQuantifierOneOfPatternIsDeterministic(t *testing.T) {
+	evaluator := newRuleEvaluatorWithSearches(
+		"selection_b", "selection_a", "selection_c", "other")
+
+	var calls []string
+	resolve := resolverFromMap(map[string]bool{}, &calls)
+
+	matched, err := evaluator.evaluateSearchExpression(
+		sigma.OneOfPattern{Pattern: "selection_*"}, resolve)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	// Every matching identifier is visited in sorted order, regardless
+	// of Go's randomized map iteration order over Detection.Searches.
+	assert.Equal(t, []string{"selection_a", "selection_b", "selection_c"}, calls)
+}
+
+func TestEvaluateSearchQuantifierAllOfPattern(t *testing.T) {
+	evaluator := newRuleEvaluatorWithSearches("selection_a", "selection_b")
+
+	resolve := resolverFromMap(map[string]bool{
+		"selection_a": true, "selection_b": false,
+	}, nil)
+
+	matched, err := evaluator.evaluateSearchExpression(
+		sigma.AllOfPattern{Pattern: "selection_*"}, resolve)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestMatchesSearchPattern(t *testing.T) {
+	assert.True(t, matchesSearchPattern("*", "anything"))
+	assert.True(t, matchesSearchPattern("selection_*", "selection_a"))
+	assert.False(t, matchesSearchPattern("selection_*", "filter_a"))
+	assert.True(t, matchesSearchPattern("exact", "exact"))
+	assert.False(t, matchesSearchPattern("exact", "exact2"))
+}
@@ -0,0 +1,43 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/Velocidex/ordereddict"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventGetResolvesDottedFieldPaths(t *testing.T) {
+	event := NewEvent(ordereddict.NewDict().
+		Set("EventData", ordereddict.NewDict().
+			Set("TargetUserName", "alice")).
+		Set("NotADict", "plain string"))
+
+	value, pres := event.Get("EventData.TargetUserName")
+	assert.True(t, pres)
+	assert.Equal(t, "alice", value)
+
+	_, pres = event.Get("EventData.Missing")
+	assert.False(t, pres)
+
+	_, pres = event.Get("NotADict.Foo")
+	assert.False(t, pres)
+}
+
+func TestEventGetCachesResolvedValue(t *testing.T) {
+	event := NewEvent(ordereddict.NewDict().Set("Channel", "Security"))
+
+	first, pres := event.Get("Channel")
+	assert.True(t, pres)
+	assert.Equal(t, "Security", first)
+
+	// The resolved value is now held in the Event's cache.
+	assert.Equal(t, "Security", event.cache["Channel"])
+
+	// The second lookup is served from the cache rather than
+	// re-walking the dict - this is the whole point of wrapping the
+	// row in an Event.
+	second, pres := event.Get("Channel")
+	assert.True(t, pres)
+	assert.Equal(t, first, second)
+}
@@ -0,0 +1,131 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Velocidex/sigma-go"
+	"www.velocidex.com/golang/vfilter/types"
+)
+
+// evaluateSearch matches a single named search's EventMatchers (or
+// Keywords) against event.
+//
+// This only implements Sigma's default, no-modifier comparison (plain
+// equality, via the same scope.Eq the rest of the evaluator uses to
+// compare VQL values) - the field-matcher modifier pipeline
+// (startswith/endswith/contains/re/base64/cidr/...) is implemented by
+// the evaluator/modifiers package, which pulls in the wider VQL
+// function subsystem and is out of scope for this change.
+func (self *VQLRuleEvaluator) evaluateSearch(
+	ctx context.Context, scope types.Scope,
+	search sigma.Search, event *Event) (bool, error) {
+
+	if len(search.Keywords) > 0 {
+		event_str := event.AsJson()
+
+		// A keyword match occurs over the entire event.
+		for _, kw := range search.Keywords {
+			if strings.Contains(event_str, strings.ToLower(kw)) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	if len(search.EventMatchers) == 0 {
+		// degenerate case (but common for logsource conditions)
+		return true, nil
+	}
+
+	// A Search is a series of EventMatchers (usually one). Each
+	// EventMatcher is a series of "does this field match this value"
+	// conditions; all fields need to match for an EventMatcher to
+	// match, but only one EventMatcher needs to match for the Search
+	// to evaluate to true.
+eventMatcher:
+	for _, eventMatcher := range search.EventMatchers {
+		for _, fieldMatcher := range eventMatcher {
+			if len(fieldMatcher.Modifiers) > 0 {
+				return false, fmt.Errorf(
+					"sigma: field modifiers (%v) are not supported on field %q",
+					fieldMatcher.Modifiers, fieldMatcher.Field)
+			}
+
+			values, err := self.GetFieldValuesFromEvent(ctx, scope, fieldMatcher.Field, event)
+			if err != nil {
+				return false, err
+			}
+
+			if !matchesAnyExpected(scope, values, fieldMatcher.Values) {
+				// This field didn't match so the overall matcher
+				// doesn't match, try the next EventMatcher.
+				continue eventMatcher
+			}
+		}
+
+		// All fields matched!
+		return true, nil
+	}
+
+	// None of the event matchers explicitly matched.
+	return false, nil
+}
+
+// matchesAnyExpected implements Sigma's default (no modifier)
+// comparison: true if any actual value equals any expected value,
+// falling back to a stringified comparison since event logs sometimes
+// encode integers as strings while the detection uses an integer.
+func matchesAnyExpected(scope types.Scope, actual, expected []interface{}) bool {
+	for _, a := range actual {
+		for _, e := range expected {
+			if scope.Eq(a, e) {
+				return true
+			}
+			if fmt.Sprintf("%v", a) == fmt.Sprintf("%v", e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetFieldValuesFromEvent resolves field on event, applying this
+// rule's VQL field mappings (if any) first.
+func (self *VQLRuleEvaluator) GetFieldValuesFromEvent(
+	ctx context.Context, scope types.Scope,
+	field string, event *Event) ([]interface{}, error) {
+
+	for _, m := range self.fieldmappings {
+		if m.Name == field {
+			return toGenericSlice(event.Reduce(ctx, scope, field, m.Lambda)), nil
+		}
+	}
+
+	value, ok := event.Get(field)
+	if !ok {
+		return nil, nil
+	}
+
+	return toGenericSlice(value), nil
+}
+
+func toGenericSlice(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+
+	// If this isn't a slice, return a slice containing the original
+	// value.
+	if rv.Kind() != reflect.Slice {
+		return []interface{}{v}
+	}
+
+	var out []interface{}
+	for i := 0; i < rv.Len(); i++ {
+		out = append(out, rv.Index(i).Interface())
+	}
+
+	return out
+}